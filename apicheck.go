@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	_ "embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+//go:embed apihelper.py
+var apiHelperScript string
+
+// ParamSpec describes one parameter of a function or method signature, as
+// reported by apihelper.py.
+type ParamSpec struct {
+	Name       string `json:"name"`
+	Kind       string `json:"kind"` // "positional", "keyword", "vararg" or "kwarg"
+	HasDefault bool   `json:"default"`
+}
+
+// FuncSig is a function or method's parameter list.
+type FuncSig struct {
+	Params []ParamSpec `json:"params"`
+}
+
+// ClassAPI is a class's public methods.
+type ClassAPI struct {
+	Methods map[string]FuncSig `json:"methods"`
+}
+
+// ModuleAPI is one module's public surface: its top-level names, __all__
+// (if declared), and the signatures of its public functions and classes.
+type ModuleAPI struct {
+	Names     []string            `json:"names"`
+	All       []string            `json:"all"`
+	Functions map[string]FuncSig  `json:"functions"`
+	Classes   map[string]ClassAPI `json:"classes"`
+}
+
+// PackageAPI maps a module's file path, relative to the project directory,
+// to that module's API.
+type PackageAPI map[string]ModuleAPI
+
+func pythonInterpreter() string {
+	if p := os.Getenv("PY_VERSION_PYTHON"); p != "" {
+		return p
+	}
+	return "python3"
+}
+
+// extractAPI runs the bundled apihelper.py over a set of Python sources
+// (keyed by path, relative to the project directory) and returns their API.
+func extractAPI(files map[string]string) (PackageAPI, error) {
+	script, err := os.CreateTemp("", "py-version-apihelper-*.py")
+	if err != nil {
+		return nil, fmt.Errorf("failed to stage apihelper.py: %v", err)
+	}
+	defer os.Remove(script.Name())
+	if _, err := script.WriteString(apiHelperScript); err != nil {
+		script.Close()
+		return nil, fmt.Errorf("failed to stage apihelper.py: %v", err)
+	}
+	script.Close()
+
+	input, err := json.Marshal(map[string]interface{}{"files": files})
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(pythonInterpreter(), script.Name())
+	cmd.Stdin = bytes.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to run %s: %v: %s", pythonInterpreter(), err, strings.TrimSpace(stderr.String()))
+	}
+
+	var api PackageAPI
+	if err := json.Unmarshal(stdout.Bytes(), &api); err != nil {
+		return nil, fmt.Errorf("failed to parse apihelper.py output: %v", err)
+	}
+	return api, nil
+}
+
+// collectFilesAtRef reads every .py file under pkgPath as it existed at the
+// given git ref.
+func collectFilesAtRef(dir, ref, pkgPath string) (map[string]string, error) {
+	out, err := runGit(dir, "ls-tree", "-r", "--name-only", ref, "--", pkgPath)
+	if err != nil {
+		return nil, err
+	}
+	files := map[string]string{}
+	if out == "" {
+		return files, nil
+	}
+	for _, relpath := range strings.Split(out, "\n") {
+		relpath = strings.TrimSpace(relpath)
+		if !strings.HasSuffix(relpath, ".py") {
+			continue
+		}
+		content, err := runGit(dir, "show", ref+":"+relpath)
+		if err != nil {
+			return nil, err
+		}
+		files[relpath] = content
+	}
+	return files, nil
+}
+
+// collectFilesOnDisk reads every .py file under pkgPath in the working tree.
+func collectFilesOnDisk(dir, pkgPath string) (map[string]string, error) {
+	root := filepath.Join(dir, pkgPath)
+	files := map[string]string{}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".py") {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = string(content)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// discoverPackageName reads the package name out of the [project] or
+// [tool.poetry] table of a pyproject.toml.
+func discoverPackageName(content string) string {
+	currentTable := ""
+	for _, rawLine := range strings.Split(content, "\n") {
+		line := strings.TrimSpace(rawLine)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if m := tomlTableHeaderPattern.FindStringSubmatch(line); m != nil {
+			currentTable = strings.TrimSpace(m[1])
+			continue
+		}
+		if currentTable != "project" && currentTable != "tool.poetry" {
+			continue
+		}
+		if m := tomlKeyValuePattern.FindStringSubmatch(line); m != nil && m[1] == "name" {
+			return unquoteTOMLString(strings.TrimSpace(m[2]))
+		}
+	}
+	return ""
+}
+
+// discoverPackagePath guesses a package's source directory from its
+// declared name, trying the conventional src/ layout first.
+func discoverPackagePath(repoDir, pkgName string) (string, error) {
+	normalized := strings.ReplaceAll(strings.ToLower(pkgName), "-", "_")
+	candidates := []string{filepath.Join("src", normalized), normalized}
+	for _, c := range candidates {
+		if info, err := os.Stat(filepath.Join(repoDir, c)); err == nil && info.IsDir() {
+			return c, nil
+		}
+	}
+	return "", fmt.Errorf("could not find a package directory for %q (tried %s); pass --package explicitly", pkgName, strings.Join(candidates, ", "))
+}
+
+// APIDiff is one observed difference between two versions of a package's
+// public API, classified by the semver bump it requires.
+type APIDiff struct {
+	Level  string // "major", "minor" or "patch"
+	Reason string
+}
+
+var bumpRank = map[string]int{"patch": 0, "minor": 1, "major": 2}
+
+// diffAPI compares two PackageAPI snapshots and returns every observed
+// difference, classified per gorelease's rules: a removed symbol or a change
+// to a signature's required parameters is major, an added symbol or an
+// added parameter (with the required ones unchanged) is minor, everything
+// else is patch. Removing an optional parameter is not reported.
+func diffAPI(old, new PackageAPI) []APIDiff {
+	var diffs []APIDiff
+
+	for relpath, oldMod := range old {
+		newMod, ok := new[relpath]
+		if !ok {
+			diffs = append(diffs, APIDiff{"major", fmt.Sprintf("%s: module removed", relpath)})
+			continue
+		}
+		diffs = append(diffs, diffModule(relpath, oldMod, newMod)...)
+	}
+	for relpath := range new {
+		if _, ok := old[relpath]; !ok {
+			diffs = append(diffs, APIDiff{"minor", fmt.Sprintf("%s: module added", relpath)})
+		}
+	}
+
+	return diffs
+}
+
+func diffModule(relpath string, oldMod, newMod ModuleAPI) []APIDiff {
+	var diffs []APIDiff
+
+	oldNames := stringSet(oldMod.Names)
+	newNames := stringSet(newMod.Names)
+	for name := range oldNames {
+		if !newNames[name] {
+			diffs = append(diffs, APIDiff{"major", fmt.Sprintf("%s: public symbol %q removed", relpath, name)})
+		}
+	}
+	for name := range newNames {
+		if !oldNames[name] {
+			diffs = append(diffs, APIDiff{"minor", fmt.Sprintf("%s: public symbol %q added", relpath, name)})
+		}
+	}
+
+	for name, oldFn := range oldMod.Functions {
+		if newFn, ok := newMod.Functions[name]; ok {
+			diffs = append(diffs, diffSignature(fmt.Sprintf("%s: %s", relpath, name), oldFn, newFn)...)
+		}
+	}
+
+	for className, oldClass := range oldMod.Classes {
+		newClass, ok := newMod.Classes[className]
+		if !ok {
+			continue // reported as a removed symbol above if it was among the module's public names
+		}
+		for methodName, oldMethod := range oldClass.Methods {
+			if newMethod, ok := newClass.Methods[methodName]; ok {
+				diffs = append(diffs, diffSignature(fmt.Sprintf("%s: %s.%s", relpath, className, methodName), oldMethod, newMethod)...)
+			} else {
+				diffs = append(diffs, APIDiff{"major", fmt.Sprintf("%s: method %s.%s removed", relpath, className, methodName)})
+			}
+		}
+		for methodName := range newClass.Methods {
+			if _, ok := oldClass.Methods[methodName]; !ok {
+				diffs = append(diffs, APIDiff{"minor", fmt.Sprintf("%s: method %s.%s added", relpath, className, methodName)})
+			}
+		}
+	}
+
+	return diffs
+}
+
+func diffSignature(label string, oldFn, newFn FuncSig) []APIDiff {
+	requiredOld := requiredParamNames(oldFn)
+	requiredNew := requiredParamNames(newFn)
+	if !equalStrings(requiredOld, requiredNew) {
+		return []APIDiff{{"major", fmt.Sprintf("%s: required parameters changed (%s -> %s)",
+			label, strings.Join(requiredOld, ", "), strings.Join(requiredNew, ", "))}}
+	}
+	if len(newFn.Params) > len(oldFn.Params) {
+		return []APIDiff{{"minor", fmt.Sprintf("%s: optional parameter added", label)}}
+	}
+	return nil
+}
+
+func requiredParamNames(fn FuncSig) []string {
+	var names []string
+	for _, p := range fn.Params {
+		if (p.Kind == "positional" || p.Kind == "keyword") && !p.HasDefault {
+			names = append(names, p.Name)
+		}
+	}
+	return names
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func stringSet(xs []string) map[string]bool {
+	m := make(map[string]bool, len(xs))
+	for _, x := range xs {
+		m[x] = true
+	}
+	return m
+}
+
+// suggestBump returns the highest bump level required by diffs, defaulting
+// to "patch" when there are none.
+func suggestBump(diffs []APIDiff) string {
+	best := "patch"
+	for _, d := range diffs {
+		if bumpRank[d.Level] > bumpRank[best] {
+			best = d.Level
+		}
+	}
+	return best
+}
+
+// classifyVersionBump reports the bump level a proposed version represents
+// relative to old, based on the first release segment that increased.
+func classifyVersionBump(old, proposed Version) string {
+	if proposed.release(0) > old.release(0) {
+		return "major"
+	}
+	if proposed.release(1) > old.release(1) {
+		return "minor"
+	}
+	return "patch"
+}
+
+func bumpAtLeast(claimed, required string) bool {
+	return bumpRank[claimed] >= bumpRank[required]
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// gitTagPattern matches tags that encode a release version, with or
+// without a leading "v".
+var gitTagPattern = regexp.MustCompile(`^v?(\d.*)$`)
+
+// pseudoLocalPattern matches the local identifier produced by
+// buildPseudoVersion: "<timestamp>.g<sha>".
+var pseudoLocalPattern = regexp.MustCompile(`^(\d{14})\.g([0-9a-fA-F]{12})$`)
+
+func runGit(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return "", fmt.Errorf("git %s: %s", strings.Join(args, " "), strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("git %s: %v", strings.Join(args, " "), err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// taggedVersion pairs a git tag name with the PEP 440 version it parses as.
+type taggedVersion struct {
+	tag     string
+	version Version
+}
+
+// ancestorTags returns every tag reachable from HEAD whose name parses as a
+// PEP 440 version (with or without a leading "v"), in no particular order.
+func ancestorTags(dir string) ([]taggedVersion, error) {
+	out, err := runGit(dir, "tag", "--merged", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	if out == "" {
+		return nil, nil
+	}
+
+	var tags []taggedVersion
+	for _, candidate := range strings.Split(out, "\n") {
+		candidate = strings.TrimSpace(candidate)
+		match := gitTagPattern.FindStringSubmatch(candidate)
+		if match == nil {
+			continue
+		}
+		v, parseErr := parseVersion(match[1])
+		if parseErr != nil {
+			continue
+		}
+		tags = append(tags, taggedVersion{candidate, v})
+	}
+	return tags, nil
+}
+
+// highestOf returns the tag with the greatest PEP 440 version in tags.
+func highestOf(tags []taggedVersion) (tag string, version Version, ok bool) {
+	var best taggedVersion
+	found := false
+	for _, t := range tags {
+		if !found || t.version.Compare(best.version) > 0 {
+			best, found = t, true
+		}
+	}
+	return best.tag, best.version, found
+}
+
+// latestAncestorTag returns the highest PEP 440 version among all tags
+// reachable from HEAD, along with the tag name it came from. If no tag in
+// the repository parses as a version, it returns ok == false.
+func latestAncestorTag(dir string) (tag string, version Version, ok bool, err error) {
+	tags, err := ancestorTags(dir)
+	if err != nil {
+		return "", Version{}, false, err
+	}
+	tag, version, ok = highestOf(tags)
+	return tag, version, ok, nil
+}
+
+// latestAncestorReleaseTag is like latestAncestorTag but considers only
+// release tags - those without a pre-release, post-release or dev-release
+// component - so a pre-release tag can never outrank the actual latest
+// release.
+func latestAncestorReleaseTag(dir string) (tag string, version Version, ok bool, err error) {
+	tags, err := ancestorTags(dir)
+	if err != nil {
+		return "", Version{}, false, err
+	}
+	var releaseTags []taggedVersion
+	for _, t := range tags {
+		if t.version.Pre == nil && t.version.Post == nil && t.version.Dev == nil {
+			releaseTags = append(releaseTags, t)
+		}
+	}
+	tag, version, ok = highestOf(releaseTags)
+	return tag, version, ok, nil
+}
+
+// commitTimestampUTC returns the committer date of ref in UTC.
+func commitTimestampUTC(dir, ref string) (time.Time, error) {
+	out, err := runGit(dir, "log", "-1", "--format=%ct", ref)
+	if err != nil {
+		return time.Time{}, err
+	}
+	epoch, err := strconv.ParseInt(out, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse commit timestamp %q: %v", out, err)
+	}
+	return time.Unix(epoch, 0).UTC(), nil
+}
+
+// abbreviatedSHA returns the length-character abbreviated commit hash of ref.
+func abbreviatedSHA(dir, ref string, length int) (string, error) {
+	return runGit(dir, "rev-parse", fmt.Sprintf("--short=%d", length), ref)
+}
+
+// buildPseudoVersion computes a PEP 440 pseudo-version for HEAD, analogous
+// to the Go modules pseudo-version scheme: the next patch release after the
+// most recent ancestor release tag (or 0.0.0 if there is none), marked as a
+// dev release and stamped with HEAD's committer timestamp and abbreviated
+// SHA.
+func buildPseudoVersion(dir string) (Version, error) {
+	_, base, ok, err := latestAncestorReleaseTag(dir)
+	if err != nil {
+		return Version{}, err
+	}
+	if ok {
+		base.setRelease(2, base.release(2)+1)
+	} else {
+		base = Version{Release: []int{0, 0, 0}}
+	}
+	base.Dev = new(int)
+
+	ts, err := commitTimestampUTC(dir, "HEAD")
+	if err != nil {
+		return Version{}, err
+	}
+	sha, err := abbreviatedSHA(dir, "HEAD", 12)
+	if err != nil {
+		return Version{}, err
+	}
+
+	base.Local = fmt.Sprintf("%s.g%s", ts.Format("20060102150405"), sha)
+
+	return base, nil
+}
+
+// checkPseudoVersion verifies that the local identifier of v encodes the
+// committer timestamp and abbreviated SHA of HEAD. It returns a
+// human-readable diagnostic describing any drift, or "" if v matches.
+func checkPseudoVersion(dir string, v Version) (string, error) {
+	match := pseudoLocalPattern.FindStringSubmatch(v.Local)
+	if match == nil {
+		return "", fmt.Errorf("%s is not a pseudo-version (expected a local identifier like 20060102150405.gabcdef012345)", v.String())
+	}
+	wantTimestamp, wantSHA := match[1], match[2]
+
+	ts, err := commitTimestampUTC(dir, "HEAD")
+	if err != nil {
+		return "", err
+	}
+	sha, err := abbreviatedSHA(dir, "HEAD", 12)
+	if err != nil {
+		return "", err
+	}
+
+	var diagnostics []string
+	if gotTimestamp := ts.Format("20060102150405"); gotTimestamp != wantTimestamp {
+		diagnostics = append(diagnostics, fmt.Sprintf("timestamp %s does not match HEAD's committer date %s", wantTimestamp, gotTimestamp))
+	}
+	if !strings.EqualFold(sha, wantSHA) {
+		diagnostics = append(diagnostics, fmt.Sprintf("commit %s does not match HEAD %s", wantSHA, sha))
+	}
+
+	return strings.Join(diagnostics, "; "), nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// GitVerifyError reports that a version failed verification against the
+// repository's tag history, naming the offending tag or commit so callers
+// can surface it directly to the user.
+type GitVerifyError struct {
+	Ref    string
+	Reason string
+}
+
+func (e *GitVerifyError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Ref, e.Reason)
+}
+
+// tagExists reports whether name exists as a tag in the repository.
+func tagExists(dir, name string) bool {
+	cmd := exec.Command("git", "rev-parse", "--verify", "-q", "refs/tags/"+name)
+	cmd.Dir = dir
+	return cmd.Run() == nil
+}
+
+// resolveCommit returns the full commit hash that ref resolves to.
+func resolveCommit(dir, ref string) (string, error) {
+	return runGit(dir, "rev-parse", ref)
+}
+
+// isAncestor reports whether ancestor is an ancestor of (or equal to) ref.
+func isAncestor(dir, ancestor, ref string) bool {
+	cmd := exec.Command("git", "merge-base", "--is-ancestor", ancestor, ref)
+	cmd.Dir = dir
+	return cmd.Run() == nil
+}
+
+// verifyVersionAgainstGit checks a candidate new version against the
+// repository's tag history before it is allowed to be written:
+//
+//  1. if a tag "v<version>" or "<version>" already exists, HEAD must equal
+//     or descend from it;
+//  2. the new version must be strictly greater, in PEP 440 ordering, than
+//     the highest release tag reachable from HEAD;
+//  3. if the new version is a pseudo-version, its embedded short SHA must
+//     resolve to an ancestor of HEAD and its embedded timestamp must match
+//     that commit's committer date.
+func verifyVersionAgainstGit(dir string, v Version) error {
+	for _, candidate := range []string{"v" + v.String(), v.String()} {
+		if !tagExists(dir, candidate) {
+			continue
+		}
+		if !isAncestor(dir, candidate, "HEAD") {
+			return &GitVerifyError{Ref: candidate, Reason: "tag already exists and HEAD is not a descendant of it"}
+		}
+	}
+
+	if highestTag, highest, ok, err := latestAncestorReleaseTag(dir); err != nil {
+		return err
+	} else if ok && v.Compare(highest) <= 0 {
+		return &GitVerifyError{Ref: highestTag, Reason: fmt.Sprintf("%s is not strictly greater than the highest existing release tag %s", v.String(), highest.String())}
+	}
+
+	if match := pseudoLocalPattern.FindStringSubmatch(v.Local); match != nil {
+		wantTimestamp, shortSHA := match[1], match[2]
+
+		commit, err := resolveCommit(dir, shortSHA)
+		if err != nil {
+			return &GitVerifyError{Ref: shortSHA, Reason: "does not resolve to a known commit"}
+		}
+		if !isAncestor(dir, commit, "HEAD") {
+			return &GitVerifyError{Ref: commit, Reason: "is not an ancestor of HEAD"}
+		}
+		ts, err := commitTimestampUTC(dir, commit)
+		if err != nil {
+			return err
+		}
+		if gotTimestamp := ts.Format("20060102150405"); gotTimestamp != wantTimestamp {
+			return &GitVerifyError{Ref: commit, Reason: fmt.Sprintf("committer date %s does not match encoded timestamp %s", gotTimestamp, wantTimestamp)}
+		}
+	}
+
+	return nil
+}
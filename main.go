@@ -11,235 +11,538 @@ import (
 	"github.com/spf13/cobra"
 )
 
-// Version represents a semantic version with major, minor, and patch components
-type Version struct {
-	Major int
-	Minor int
-	Patch int
-}
-
-func parseVersion(versionStr string) (Version, error) {
-	re := regexp.MustCompile(`^(\d+)\.(\d+)\.(\d+)$`)
-	matches := re.FindStringSubmatch(versionStr)
-	
-	if matches == nil || len(matches) != 4 {
-		return Version{}, fmt.Errorf("invalid version format: %s", versionStr)
-	}
-	
-	major, _ := strconv.Atoi(matches[1])
-	minor, _ := strconv.Atoi(matches[2])
-	patch, _ := strconv.Atoi(matches[3])
-	
-	return Version{Major: major, Minor: minor, Patch: patch}, nil
-}
-
-func (v Version) String() string {
-	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
-}
-
 func findProjectFiles(specifiedFiles []string) ([]string, error) {
 	if len(specifiedFiles) > 0 {
 		return specifiedFiles, nil
 	}
-	
+
 	currentDir, err := os.Getwd()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get current directory: %v", err)
 	}
-	
+
 	defaultFile := filepath.Join(currentDir, "pyproject.toml")
 	if _, err := os.Stat(defaultFile); err == nil {
 		return []string{defaultFile}, nil
 	}
-	
+
 	return nil, fmt.Errorf("no pyproject.toml found in current directory")
 }
 
-func updateVersion(filePath string, updateFunc func(Version) Version) error {
+var versionAssignmentPattern = regexp.MustCompile(`(version\s*=\s*["'])([^"']+)(["'])`)
+
+func updateVersion(filePath string, updateFunc func(Version) (Version, error)) error {
 	content, err := os.ReadFile(filePath)
 	if err != nil {
 		return fmt.Errorf("failed to read file %s: %v", filePath, err)
 	}
-	
+
 	fileContent := string(content)
-	
-	re := regexp.MustCompile(`(version\s*=\s*["'])(\d+\.\d+\.\d+)(["'])`)
-	match := re.FindStringSubmatch(fileContent)
-	
+
+	match := versionAssignmentPattern.FindStringSubmatch(fileContent)
+
 	if match == nil || len(match) != 4 {
 		return fmt.Errorf("version not found in %s", filePath)
 	}
-	
+
 	oldVersion, err := parseVersion(match[2])
 	if err != nil {
 		return err
 	}
-	
-	newVersion := updateFunc(oldVersion)
-	
-	updatedContent := re.ReplaceAllString(
-		fileContent, 
+
+	newVersion, err := updateFunc(oldVersion)
+	if err != nil {
+		return err
+	}
+
+	updatedContent := versionAssignmentPattern.ReplaceAllString(
+		fileContent,
 		fmt.Sprintf("${1}%s${3}", newVersion.String()),
 	)
-	
-	err = os.WriteFile(filePath, []byte(updatedContent), 0644)
+
+	writes := []pendingWrite{{path: filePath, original: content, updated: []byte(updatedContent)}}
+
+	rules, err := parseSyncRules(fileContent)
 	if err != nil {
-		return fmt.Errorf("failed to write to file %s: %v", filePath, err)
+		return fmt.Errorf("failed to parse [tool.py-version] sync rules in %s: %v", filePath, err)
+	}
+	syncWrites, err := prepareSyncWrites(filepath.Dir(filePath), rules, newVersion)
+	if err != nil {
+		return err
 	}
-	
+	writes = append(writes, syncWrites...)
+
+	if err := commitWrites(writes); err != nil {
+		return err
+	}
+
 	fmt.Printf("Updated %s: %s → %s\n", filePath, oldVersion.String(), newVersion.String())
 	return nil
 }
 
+var validComponents = map[string]bool{
+	"major": true,
+	"minor": true,
+	"patch": true,
+	"epoch": true,
+	"pre":   true,
+	"post":  true,
+	"dev":   true,
+	"local": true,
+}
+
 func validateComponent(component string) error {
-	validComponents := map[string]bool{
-		"major": true,
-		"minor": true,
-		"patch": true,
-	}
-	
 	if !validComponents[strings.ToLower(component)] {
-		return fmt.Errorf("invalid component: %s (must be 'major', 'minor', or 'patch')", component)
+		return fmt.Errorf("invalid component: %s (must be one of major, minor, patch, epoch, pre, post, dev, local)", component)
 	}
-	
+
 	return nil
 }
 
+// shiftComponent applies a signed amount to the named version component,
+// clamping numeric components at 0. It is shared by the increment and
+// decrement commands, which differ only in the sign of amount.
+func shiftComponent(v Version, component string, amount int) (Version, error) {
+	switch component {
+	case "major":
+		v.setRelease(0, max(0, v.release(0)+amount))
+		v.setRelease(1, 0)
+		v.setRelease(2, 0)
+	case "minor":
+		v.setRelease(1, max(0, v.release(1)+amount))
+		v.setRelease(2, 0)
+	case "patch":
+		v.setRelease(2, max(0, v.release(2)+amount))
+	case "epoch":
+		v.Epoch = max(0, v.Epoch+amount)
+	case "pre":
+		if v.Pre == nil {
+			return v, fmt.Errorf("version has no pre-release segment to adjust (use bump-pre to introduce one)")
+		}
+		n := max(0, v.Pre.N+amount)
+		v.Pre = &PreRelease{Label: v.Pre.Label, N: n}
+	case "post":
+		post := 0
+		if v.Post != nil {
+			post = *v.Post
+		}
+		post = max(0, post+amount)
+		v.Post = &post
+	case "dev":
+		dev := 0
+		if v.Dev != nil {
+			dev = *v.Dev
+		}
+		dev = max(0, dev+amount)
+		v.Dev = &dev
+	case "local":
+		return v, fmt.Errorf("local component cannot be incremented or decremented, use set instead")
+	}
+	return v, nil
+}
+
+var preComponentPattern = regexp.MustCompile(`^(a|b|c|rc|alpha|beta|pre|preview)([0-9]*)$`)
+
+// setComponent parses value according to the target component and returns
+// the resulting version. Numeric components take a non-negative integer;
+// pre takes a label optionally followed by a number (e.g. "rc2"); local
+// takes a raw local version identifier.
+func setComponent(v Version, component, value string) (Version, error) {
+	switch component {
+	case "major", "minor", "patch", "epoch", "post", "dev":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return v, fmt.Errorf("invalid value: %s", value)
+		}
+		if n < 0 {
+			return v, fmt.Errorf("version components cannot be negative")
+		}
+		switch component {
+		case "major":
+			v.setRelease(0, n)
+		case "minor":
+			v.setRelease(1, n)
+		case "patch":
+			v.setRelease(2, n)
+		case "epoch":
+			v.Epoch = n
+		case "post":
+			v.Post = &n
+		case "dev":
+			v.Dev = &n
+		}
+	case "pre":
+		match := preComponentPattern.FindStringSubmatch(value)
+		if match == nil {
+			return v, fmt.Errorf("invalid pre-release value: %s (expected a label such as a, b or rc, optionally followed by a number)", value)
+		}
+		n := 0
+		if match[2] != "" {
+			n, _ = strconv.Atoi(match[2])
+		}
+		v.Pre = &PreRelease{Label: normalizePreLabel(match[1]), N: n}
+	case "local":
+		v.Local = value
+	}
+	return v, nil
+}
+
+// withGitVerification wraps an updateFunc so that, when verifyGit is set, the
+// version it produces is checked against the repository's tag history before
+// being accepted. force bypasses a failed check.
+func withGitVerification(base func(Version) (Version, error), verifyGit, force bool) func(Version) (Version, error) {
+	if !verifyGit {
+		return base
+	}
+	return func(v Version) (Version, error) {
+		newVersion, err := base(v)
+		if err != nil {
+			return newVersion, err
+		}
+		repoDir, err := os.Getwd()
+		if err != nil {
+			return newVersion, fmt.Errorf("failed to get current directory: %v", err)
+		}
+		if err := verifyVersionAgainstGit(repoDir, newVersion); err != nil && !force {
+			return newVersion, fmt.Errorf("git verification failed (use --force to override): %v", err)
+		}
+		return newVersion, nil
+	}
+}
+
 func main() {
 	var files []string
 	var amount int
-	
+	var verifyGit bool
+	var force bool
+
 	rootCmd := &cobra.Command{
 		Use:   "py-version",
 		Short: "A tool to manage version numbers in pyproject.toml files",
 	}
-	
+
 	incrementCmd := &cobra.Command{
 		Use:   "increment [component]",
-		Short: "Increment a version component (major, minor, or patch)",
+		Short: "Increment a version component (major, minor, patch, epoch, pre, post or dev)",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			component := strings.ToLower(args[0])
-			
+
 			if err := validateComponent(component); err != nil {
 				return err
 			}
-			
+
 			projectFiles, err := findProjectFiles(files)
 			if err != nil {
 				return err
 			}
-			
-			updateFunc := func(v Version) Version {
-				switch component {
-				case "major":
-					v.Major += amount
-					v.Minor = 0
-					v.Patch = 0
-				case "minor":
-					v.Minor += amount
-					v.Patch = 0
-				case "patch":
-					v.Patch += amount
-				}
-				return v
-			}
-			
+
+			updateFunc := withGitVerification(func(v Version) (Version, error) {
+				return shiftComponent(v, component, amount)
+			}, verifyGit, force)
+
 			for _, file := range projectFiles {
 				if err := updateVersion(file, updateFunc); err != nil {
 					return err
 				}
 			}
-			
+
 			return nil
 		},
 	}
-	
+
 	decrementCmd := &cobra.Command{
 		Use:   "decrement [component]",
-		Short: "Decrement a version component (major, minor, or patch)",
+		Short: "Decrement a version component (major, minor, patch, epoch, pre, post or dev)",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			component := strings.ToLower(args[0])
-			
+
 			if err := validateComponent(component); err != nil {
 				return err
 			}
-			
+
 			projectFiles, err := findProjectFiles(files)
 			if err != nil {
 				return err
 			}
-			
-			updateFunc := func(v Version) Version {
-				switch component {
-				case "major":
-					v.Major = max(0, v.Major-amount)
-				case "minor":
-					v.Minor = max(0, v.Minor-amount)
-				case "patch":
-					v.Patch = max(0, v.Patch-amount)
-				}
-				return v
-			}
-			
+
+			updateFunc := withGitVerification(func(v Version) (Version, error) {
+				return shiftComponent(v, component, -amount)
+			}, verifyGit, force)
+
 			for _, file := range projectFiles {
 				if err := updateVersion(file, updateFunc); err != nil {
 					return err
 				}
 			}
-			
+
 			return nil
 		},
 	}
-	
+
 	setCmd := &cobra.Command{
 		Use:   "set [component] [value]",
 		Short: "Set a version component to a specific value",
 		Args:  cobra.ExactArgs(2),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			component := strings.ToLower(args[0])
-			
+			value := args[1]
+
 			if err := validateComponent(component); err != nil {
 				return err
 			}
-			
-			value, err := strconv.Atoi(args[1])
+
+			projectFiles, err := findProjectFiles(files)
 			if err != nil {
-				return fmt.Errorf("invalid value: %s", args[1])
+				return err
 			}
-			
-			if value < 0 {
-				return fmt.Errorf("version components cannot be negative")
+
+			updateFunc := withGitVerification(func(v Version) (Version, error) {
+				return setComponent(v, component, value)
+			}, verifyGit, force)
+
+			for _, file := range projectFiles {
+				if err := updateVersion(file, updateFunc); err != nil {
+					return err
+				}
 			}
-			
+
+			return nil
+		},
+	}
+
+	bumpPreCmd := &cobra.Command{
+		Use:   "bump-pre [alpha|beta|rc]",
+		Short: "Introduce or advance a pre-release segment",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			label := normalizePreLabel(args[0])
+			if _, ok := preReleaseRank[label]; !ok {
+				return fmt.Errorf("invalid pre-release label: %s (must be alpha, beta or rc)", args[0])
+			}
+
 			projectFiles, err := findProjectFiles(files)
 			if err != nil {
 				return err
 			}
-			
-			updateFunc := func(v Version) Version {
-				switch component {
-				case "major":
-					v.Major = value
-				case "minor":
-					v.Minor = value
-				case "patch":
-					v.Patch = value
+
+			updateFunc := func(v Version) (Version, error) {
+				switch {
+				case v.Pre == nil:
+					v.Pre = &PreRelease{Label: label, N: 1}
+				case v.Pre.Label == label:
+					v.Pre = &PreRelease{Label: label, N: v.Pre.N + 1}
+				case preReleaseRank[label] < preReleaseRank[v.Pre.Label]:
+					return v, fmt.Errorf("cannot move pre-release backward from %s to %s", v.Pre.Label, label)
+				default:
+					v.Pre = &PreRelease{Label: label, N: 1}
 				}
-				return v
+				return v, nil
 			}
-			
+
 			for _, file := range projectFiles {
 				if err := updateVersion(file, updateFunc); err != nil {
 					return err
 				}
 			}
-			
+
 			return nil
 		},
 	}
-	
+
+	finalizeCmd := &cobra.Command{
+		Use:   "finalize",
+		Short: "Strip pre-release, post-release and dev-release segments to produce a release version",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			projectFiles, err := findProjectFiles(files)
+			if err != nil {
+				return err
+			}
+
+			updateFunc := func(v Version) (Version, error) {
+				v.Pre = nil
+				v.Post = nil
+				v.Dev = nil
+				return v, nil
+			}
+
+			for _, file := range projectFiles {
+				if err := updateVersion(file, updateFunc); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+	}
+
+	var pseudoWrite bool
+	var pseudoCheck bool
+	pseudoCmd := &cobra.Command{
+		Use:   "pseudo",
+		Short: "Generate a PEP 440 pseudo-version from the current git state",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repoDir, err := os.Getwd()
+			if err != nil {
+				return fmt.Errorf("failed to get current directory: %v", err)
+			}
+
+			if pseudoCheck {
+				projectFiles, err := findProjectFiles(files)
+				if err != nil {
+					return err
+				}
+				for _, file := range projectFiles {
+					content, err := os.ReadFile(file)
+					if err != nil {
+						return fmt.Errorf("failed to read file %s: %v", file, err)
+					}
+					match := versionAssignmentPattern.FindStringSubmatch(string(content))
+					if match == nil || len(match) != 4 {
+						return fmt.Errorf("version not found in %s", file)
+					}
+					v, err := parseVersion(match[2])
+					if err != nil {
+						return err
+					}
+					diagnostic, err := checkPseudoVersion(repoDir, v)
+					if err != nil {
+						return fmt.Errorf("%s: %v", file, err)
+					}
+					if diagnostic != "" {
+						return fmt.Errorf("%s: %s has drifted from HEAD: %s", file, v.String(), diagnostic)
+					}
+					fmt.Printf("%s: %s matches HEAD\n", file, v.String())
+				}
+				return nil
+			}
+
+			pseudoVersion, err := buildPseudoVersion(repoDir)
+			if err != nil {
+				return err
+			}
+
+			if pseudoWrite {
+				projectFiles, err := findProjectFiles(files)
+				if err != nil {
+					return err
+				}
+				updateFunc := func(Version) (Version, error) {
+					return pseudoVersion, nil
+				}
+				for _, file := range projectFiles {
+					if err := updateVersion(file, updateFunc); err != nil {
+						return err
+					}
+				}
+				return nil
+			}
+
+			fmt.Println(pseudoVersion.String())
+			return nil
+		},
+	}
+
+	var checkBase string
+	var checkPackage string
+	var checkSuggest bool
+	checkCmd := &cobra.Command{
+		Use:   "check [new-version]",
+		Short: "Compare a package's public API across a version bump, like gorelease for Python",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repoDir, err := os.Getwd()
+			if err != nil {
+				return fmt.Errorf("failed to get current directory: %v", err)
+			}
+
+			projectFiles, err := findProjectFiles(files)
+			if err != nil {
+				return err
+			}
+			content, err := os.ReadFile(projectFiles[0])
+			if err != nil {
+				return fmt.Errorf("failed to read file %s: %v", projectFiles[0], err)
+			}
+			match := versionAssignmentPattern.FindStringSubmatch(string(content))
+			if match == nil || len(match) != 4 {
+				return fmt.Errorf("version not found in %s", projectFiles[0])
+			}
+			oldVersion, err := parseVersion(match[2])
+			if err != nil {
+				return err
+			}
+
+			baseRef := checkBase
+			if baseRef == "" {
+				tag, _, ok, err := latestAncestorReleaseTag(repoDir)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					return fmt.Errorf("no --base given and no release tag found in the repository")
+				}
+				baseRef = tag
+			}
+
+			pkgName := discoverPackageName(string(content))
+			if pkgName == "" {
+				return fmt.Errorf("could not determine the package name from %s", projectFiles[0])
+			}
+			pkgPath := checkPackage
+			if pkgPath == "" {
+				pkgPath, err = discoverPackagePath(repoDir, pkgName)
+				if err != nil {
+					return err
+				}
+			}
+
+			baseFiles, err := collectFilesAtRef(repoDir, baseRef, pkgPath)
+			if err != nil {
+				return fmt.Errorf("failed to read %s at %s: %v", pkgPath, baseRef, err)
+			}
+			headFiles, err := collectFilesOnDisk(repoDir, pkgPath)
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %v", pkgPath, err)
+			}
+
+			baseAPI, err := extractAPI(baseFiles)
+			if err != nil {
+				return fmt.Errorf("failed to extract API at %s: %v", baseRef, err)
+			}
+			headAPI, err := extractAPI(headFiles)
+			if err != nil {
+				return fmt.Errorf("failed to extract API of the working tree: %v", err)
+			}
+
+			diffs := diffAPI(baseAPI, headAPI)
+			for _, d := range diffs {
+				fmt.Printf("%s: %s\n", d.Level, d.Reason)
+			}
+
+			suggested := suggestBump(diffs)
+			fmt.Printf("suggested bump: %s (%s -> working tree, package %s)\n", suggested, baseRef, pkgPath)
+
+			if checkSuggest || len(args) == 0 {
+				return nil
+			}
+
+			newVersion, err := parseVersion(args[0])
+			if err != nil {
+				return err
+			}
+			claimed := classifyVersionBump(oldVersion, newVersion)
+			if !bumpAtLeast(claimed, suggested) {
+				return fmt.Errorf("proposed version %s is a %s bump, but the API diff requires at least a %s bump", newVersion.String(), claimed, suggested)
+			}
+			fmt.Printf("%s bump from %s to %s is consistent with the API diff\n", claimed, oldVersion.String(), newVersion.String())
+			return nil
+		},
+	}
+
 	showCmd := &cobra.Command{
 		Use:   "show",
 		Short: "Display the current version without modifying it",
@@ -248,40 +551,63 @@ func main() {
 			if err != nil {
 				return err
 			}
-			
+
 			for _, file := range projectFiles {
 				content, err := os.ReadFile(file)
 				if err != nil {
 					return fmt.Errorf("failed to read file %s: %v", file, err)
 				}
-				
-				re := regexp.MustCompile(`version\s*=\s*["'](\d+\.\d+\.\d+)["']`)
-				match := re.FindStringSubmatch(string(content))
-				
-				if match == nil || len(match) != 2 {
+
+				match := versionAssignmentPattern.FindStringSubmatch(string(content))
+
+				if match == nil || len(match) != 4 {
 					return fmt.Errorf("version not found in %s", file)
 				}
-				
-				fmt.Printf("%s: %s\n", file, match[1])
+
+				version, err := parseVersion(match[2])
+				if err != nil {
+					return err
+				}
+
+				fmt.Printf("%s: %s\n", file, version.String())
 			}
-			
+
 			return nil
 		},
 	}
-	
+
 	// Add flags
 	incrementCmd.Flags().StringSliceVarP(&files, "files", "f", nil, "Files to update (default: pyproject.toml in current dir)")
 	incrementCmd.Flags().IntVar(&amount, "amount", 1, "Amount to increment by")
-	
+	incrementCmd.Flags().BoolVar(&verifyGit, "verify-git", false, "Refuse to write unless the new version is consistent with git tag history")
+	incrementCmd.Flags().BoolVar(&force, "force", false, "Override a failed --verify-git check")
+
 	decrementCmd.Flags().StringSliceVarP(&files, "files", "f", nil, "Files to update (default: pyproject.toml in current dir)")
 	decrementCmd.Flags().IntVar(&amount, "amount", 1, "Amount to decrement by")
-	
+	decrementCmd.Flags().BoolVar(&verifyGit, "verify-git", false, "Refuse to write unless the new version is consistent with git tag history")
+	decrementCmd.Flags().BoolVar(&force, "force", false, "Override a failed --verify-git check")
+
 	setCmd.Flags().StringSliceVarP(&files, "files", "f", nil, "Files to update (default: pyproject.toml in current dir)")
-	
+	setCmd.Flags().BoolVar(&verifyGit, "verify-git", false, "Refuse to write unless the new version is consistent with git tag history")
+	setCmd.Flags().BoolVar(&force, "force", false, "Override a failed --verify-git check")
+
+	bumpPreCmd.Flags().StringSliceVarP(&files, "files", "f", nil, "Files to update (default: pyproject.toml in current dir)")
+
+	finalizeCmd.Flags().StringSliceVarP(&files, "files", "f", nil, "Files to update (default: pyproject.toml in current dir)")
+
+	pseudoCmd.Flags().StringSliceVarP(&files, "files", "f", nil, "Files to update (default: pyproject.toml in current dir)")
+	pseudoCmd.Flags().BoolVar(&pseudoWrite, "write", false, "Write the generated pseudo-version into the discovered file")
+	pseudoCmd.Flags().BoolVar(&pseudoCheck, "check", false, "Verify that an existing pseudo-version still matches HEAD")
+
 	showCmd.Flags().StringSliceVarP(&files, "files", "f", nil, "Files to show version from (default: pyproject.toml in current dir)")
-	
-	rootCmd.AddCommand(incrementCmd, decrementCmd, setCmd, showCmd)
-	
+
+	checkCmd.Flags().StringSliceVarP(&files, "files", "f", nil, "Files to read the current version from (default: pyproject.toml in current dir)")
+	checkCmd.Flags().StringVar(&checkBase, "base", "", "Git ref to compare against (default: latest release tag reachable from HEAD)")
+	checkCmd.Flags().StringVar(&checkPackage, "package", "", "Path to the package's source directory (default: guessed from the project name)")
+	checkCmd.Flags().BoolVar(&checkSuggest, "suggest", false, "Only print the recommended bump, without checking a proposed version")
+
+	rootCmd.AddCommand(incrementCmd, decrementCmd, setCmd, bumpPreCmd, finalizeCmd, pseudoCmd, checkCmd, showCmd)
+
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
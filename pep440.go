@@ -0,0 +1,266 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// preReleaseRank orders normalized pre-release labels for comparison purposes.
+var preReleaseRank = map[string]int{
+	"a":  0,
+	"b":  1,
+	"rc": 2,
+}
+
+// normalizePreLabel maps the various spellings PEP 440 allows for a
+// pre-release segment onto the three canonical labels used internally.
+func normalizePreLabel(label string) string {
+	switch strings.ToLower(label) {
+	case "a", "alpha":
+		return "a"
+	case "b", "beta":
+		return "b"
+	case "c", "rc", "pre", "preview":
+		return "rc"
+	default:
+		return strings.ToLower(label)
+	}
+}
+
+// PreRelease represents the pre-release segment of a PEP 440 version,
+// e.g. the "rc2" in "1.2.3rc2".
+type PreRelease struct {
+	Label string // normalized to "a", "b" or "rc"
+	N     int
+}
+
+func (p PreRelease) String() string {
+	return fmt.Sprintf("%s%d", p.Label, p.N)
+}
+
+// Version represents a PEP 440 compliant version: an optional epoch, an
+// arbitrary-length release segment, an optional pre-release, post-release,
+// dev-release, and a local version identifier.
+type Version struct {
+	Epoch   int
+	Release []int
+	Pre     *PreRelease
+	Post    *int
+	Dev     *int
+	Local   string
+}
+
+var versionPattern = regexp.MustCompile(
+	`(?i)^\s*v?` +
+		`(?:(?P<epoch>[0-9]+)!)?` +
+		`(?P<release>[0-9]+(?:\.[0-9]+)*)` +
+		`(?:[-_.]?(?P<prel>a|b|c|rc|alpha|beta|pre|preview)(?:[-_.]?(?P<pren>[0-9]+))?)?` +
+		`(?:(?:-(?P<postn1>[0-9]+))|(?:[-_.]?(?P<postl>post|rev|r)(?:[-_.]?(?P<postn2>[0-9]+))?))?` +
+		`(?:[-_.]?(?P<devl>dev)(?:[-_.]?(?P<devn>[0-9]+))?)?` +
+		`(?:\+(?P<local>[a-zA-Z0-9]+(?:[-_.][a-zA-Z0-9]+)*))?` +
+		`\s*$`,
+)
+
+// parseVersion parses a PEP 440 version string into a Version.
+func parseVersion(versionStr string) (Version, error) {
+	match := versionPattern.FindStringSubmatch(versionStr)
+	if match == nil {
+		return Version{}, fmt.Errorf("invalid version format: %s", versionStr)
+	}
+	names := versionPattern.SubexpNames()
+	get := func(name string) string {
+		for i, n := range names {
+			if n == name {
+				return match[i]
+			}
+		}
+		return ""
+	}
+
+	v := Version{}
+
+	if epoch := get("epoch"); epoch != "" {
+		v.Epoch, _ = strconv.Atoi(epoch)
+	}
+
+	for _, part := range strings.Split(get("release"), ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return Version{}, fmt.Errorf("invalid version format: %s", versionStr)
+		}
+		v.Release = append(v.Release, n)
+	}
+
+	if label := get("prel"); label != "" {
+		n := 0
+		if preN := get("pren"); preN != "" {
+			n, _ = strconv.Atoi(preN)
+		}
+		v.Pre = &PreRelease{Label: normalizePreLabel(label), N: n}
+	}
+
+	if postN := get("postn1"); postN != "" {
+		n, _ := strconv.Atoi(postN)
+		v.Post = &n
+	} else if get("postl") != "" {
+		n := 0
+		if postN2 := get("postn2"); postN2 != "" {
+			n, _ = strconv.Atoi(postN2)
+		}
+		v.Post = &n
+	}
+
+	if get("devl") != "" {
+		n := 0
+		if devN := get("devn"); devN != "" {
+			n, _ = strconv.Atoi(devN)
+		}
+		v.Dev = &n
+	}
+
+	v.Local = get("local")
+
+	return v, nil
+}
+
+// release returns the release segment at index i, treating segments past
+// the end of v.Release as 0.
+func (v Version) release(i int) int {
+	if i < len(v.Release) {
+		return v.Release[i]
+	}
+	return 0
+}
+
+// setRelease sets the release segment at index i, extending v.Release
+// with zeros as needed. It always allocates a fresh backing array so that
+// Version values which still share a backing array after a plain struct
+// copy (e.g. the old/new versions in updateVersion) never alias each
+// other's writes.
+func (v *Version) setRelease(i, value int) {
+	release := make([]int, len(v.Release))
+	copy(release, v.Release)
+	for len(release) <= i {
+		release = append(release, 0)
+	}
+	release[i] = value
+	v.Release = release
+}
+
+func (v Version) String() string {
+	var b strings.Builder
+
+	if v.Epoch != 0 {
+		fmt.Fprintf(&b, "%d!", v.Epoch)
+	}
+
+	segments := make([]string, len(v.Release))
+	for i, n := range v.Release {
+		segments[i] = strconv.Itoa(n)
+	}
+	b.WriteString(strings.Join(segments, "."))
+
+	if v.Pre != nil {
+		b.WriteString(v.Pre.String())
+	}
+	if v.Post != nil {
+		fmt.Fprintf(&b, ".post%d", *v.Post)
+	}
+	if v.Dev != nil {
+		fmt.Fprintf(&b, ".dev%d", *v.Dev)
+	}
+	if v.Local != "" {
+		fmt.Fprintf(&b, "+%s", v.Local)
+	}
+
+	return b.String()
+}
+
+// Compare returns -1, 0 or 1 if v is less than, equal to or greater than
+// other, following PEP 440 ordering. The local version identifier is not
+// considered, matching the spec's guidance that local versions are only
+// partially ordered against each other.
+func (v Version) Compare(other Version) int {
+	if v.Epoch != other.Epoch {
+		return cmpInt(v.Epoch, other.Epoch)
+	}
+
+	maxLen := len(v.Release)
+	if len(other.Release) > maxLen {
+		maxLen = len(other.Release)
+	}
+	for i := 0; i < maxLen; i++ {
+		if c := cmpInt(v.release(i), other.release(i)); c != 0 {
+			return c
+		}
+	}
+
+	if c := cmpInt(v.preRank(), other.preRank()); c != 0 {
+		return c
+	}
+	if c := cmpInt(v.preN(), other.preN()); c != 0 {
+		return c
+	}
+	if c := cmpInt(v.postN(), other.postN()); c != 0 {
+		return c
+	}
+	return cmpInt(v.devN(), other.devN())
+}
+
+// The following two ranks implement PEP 440's "dev < pre < (no suffix) <
+// post" rule: a dev-only release (no pre, no post) sorts before any
+// pre-release, and a final or post release (no pre) sorts after any
+// pre-release.
+const (
+	rankBelowAnyPre = -1 << 30
+	rankAboveAnyPre = 1 << 30
+)
+
+func (v Version) preRank() int {
+	switch {
+	case v.Pre != nil:
+		return preReleaseRank[v.Pre.Label]
+	case v.Post == nil && v.Dev != nil:
+		return rankBelowAnyPre
+	default:
+		return rankAboveAnyPre
+	}
+}
+
+func (v Version) preN() int {
+	switch {
+	case v.Pre != nil:
+		return v.Pre.N
+	case v.Post == nil && v.Dev != nil:
+		return rankBelowAnyPre
+	default:
+		return rankAboveAnyPre
+	}
+}
+
+func (v Version) postN() int {
+	if v.Post == nil {
+		return rankBelowAnyPre
+	}
+	return *v.Post
+}
+
+func (v Version) devN() int {
+	if v.Dev == nil {
+		return rankAboveAnyPre
+	}
+	return *v.Dev
+}
+
+func cmpInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SyncRule describes one additional file to keep in sync with the project
+// version, as declared in a [[tool.py-version.sync]] array-of-tables entry
+// in pyproject.toml.
+type SyncRule struct {
+	Path     string
+	Pattern  string
+	Format   string
+	Required bool
+}
+
+var tomlTableHeaderPattern = regexp.MustCompile(`^\[\[?([^\]]+)\]\]?$`)
+var tomlKeyValuePattern = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_-]*)\s*=\s*(.+)$`)
+
+// parseSyncRules extracts the [[tool.py-version.sync]] entries from a
+// pyproject.toml's contents. It understands just enough TOML to read this
+// one array-of-tables: string and boolean key/value pairs inside
+// "[[tool.py-version.sync]]" blocks.
+func parseSyncRules(pyprojectContent string) ([]SyncRule, error) {
+	var rules []SyncRule
+	var current *SyncRule
+	inSyncTable := false
+
+	flush := func() {
+		if current != nil {
+			rules = append(rules, *current)
+			current = nil
+		}
+	}
+
+	for _, rawLine := range strings.Split(pyprojectContent, "\n") {
+		line := strings.TrimSpace(rawLine)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if match := tomlTableHeaderPattern.FindStringSubmatch(line); match != nil {
+			name := strings.TrimSpace(match[1])
+			isArrayTable := strings.HasPrefix(line, "[[")
+			flush()
+			inSyncTable = isArrayTable && name == "tool.py-version.sync"
+			if inSyncTable {
+				current = &SyncRule{Required: true}
+			}
+			continue
+		}
+
+		if !inSyncTable {
+			continue
+		}
+
+		match := tomlKeyValuePattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		key, rawValue := match[1], strings.TrimSpace(match[2])
+
+		switch key {
+		case "path":
+			current.Path = unquoteTOMLString(rawValue)
+		case "pattern":
+			current.Pattern = unquoteTOMLString(rawValue)
+		case "format":
+			current.Format = unquoteTOMLString(rawValue)
+		case "required":
+			current.Required = rawValue == "true"
+		}
+	}
+	flush()
+
+	return rules, nil
+}
+
+// unquoteTOMLString strips the quotes from a TOML basic ("...") or literal
+// ('...') string, unescaping basic-string escape sequences.
+func unquoteTOMLString(raw string) string {
+	if len(raw) < 2 {
+		return raw
+	}
+	quote := raw[0]
+	if quote != '"' && quote != '\'' {
+		return raw
+	}
+	inner := raw[1 : len(raw)-1]
+	if quote == '\'' {
+		return inner
+	}
+	replacer := strings.NewReplacer(`\"`, `"`, `\\`, `\`, `\n`, "\n", `\t`, "\t")
+	return replacer.Replace(inner)
+}
+
+// compileSyncPattern compiles a sync rule's pattern and requires it to
+// contain a named capture group called "version".
+func compileSyncPattern(pattern string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %v", pattern, err)
+	}
+	if re.SubexpIndex("version") == -1 {
+		return nil, fmt.Errorf("pattern %q must contain a named capture group \"version\"", pattern)
+	}
+	return re, nil
+}
+
+// formatVersion renders a sync rule's format template, substituting
+// {major}, {minor}, {patch}, {epoch}, {pre}, {post}, {dev}, {local} and
+// {version}. An empty template defaults to "{major}.{minor}.{patch}".
+func formatVersion(format string, v Version) string {
+	if format == "" {
+		format = "{major}.{minor}.{patch}"
+	}
+
+	pre, post, dev := "", "", ""
+	if v.Pre != nil {
+		pre = v.Pre.String()
+	}
+	if v.Post != nil {
+		post = strconv.Itoa(*v.Post)
+	}
+	if v.Dev != nil {
+		dev = strconv.Itoa(*v.Dev)
+	}
+
+	replacer := strings.NewReplacer(
+		"{major}", strconv.Itoa(v.release(0)),
+		"{minor}", strconv.Itoa(v.release(1)),
+		"{patch}", strconv.Itoa(v.release(2)),
+		"{epoch}", strconv.Itoa(v.Epoch),
+		"{pre}", pre,
+		"{post}", post,
+		"{dev}", dev,
+		"{local}", v.Local,
+		"{version}", v.String(),
+	)
+	return replacer.Replace(format)
+}
+
+// pendingWrite is a file write that has been computed and validated but not
+// yet applied to disk.
+type pendingWrite struct {
+	path     string
+	original []byte
+	updated  []byte
+}
+
+// prepareSyncWrites resolves each sync rule against baseDir and computes
+// its updated content, without writing anything to disk. A required rule
+// that can't be read or whose pattern doesn't match fails the whole batch;
+// an optional one is skipped. When two rules target the same path, the
+// second is matched and replaced against the first's pending edit rather
+// than the on-disk content, so neither write clobbers the other.
+func prepareSyncWrites(baseDir string, rules []SyncRule, newVersion Version) ([]pendingWrite, error) {
+	var writes []pendingWrite
+	pendingIndex := make(map[string]int)
+
+	for _, rule := range rules {
+		if rule.Path == "" || rule.Pattern == "" {
+			return nil, fmt.Errorf("sync rule missing path or pattern")
+		}
+
+		path := rule.Path
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(baseDir, path)
+		}
+
+		var content []byte
+		if i, ok := pendingIndex[path]; ok {
+			content = writes[i].updated
+		} else {
+			diskContent, err := os.ReadFile(path)
+			if err != nil {
+				if rule.Required {
+					return nil, fmt.Errorf("required sync target %s: %v", rule.Path, err)
+				}
+				fmt.Printf("Skipping optional sync target %s: %v\n", rule.Path, err)
+				continue
+			}
+			content = diskContent
+		}
+
+		re, err := compileSyncPattern(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("sync target %s: %v", rule.Path, err)
+		}
+
+		loc := re.FindSubmatchIndex(content)
+		if loc == nil {
+			if rule.Required {
+				return nil, fmt.Errorf("required sync target %s: pattern did not match", rule.Path)
+			}
+			fmt.Printf("Skipping optional sync target %s: pattern did not match\n", rule.Path)
+			continue
+		}
+
+		groupIndex := re.SubexpIndex("version")
+		start, end := loc[2*groupIndex], loc[2*groupIndex+1]
+		replacement := formatVersion(rule.Format, newVersion)
+
+		updated := make([]byte, 0, len(content)-(end-start)+len(replacement))
+		updated = append(updated, content[:start]...)
+		updated = append(updated, replacement...)
+		updated = append(updated, content[end:]...)
+
+		if i, ok := pendingIndex[path]; ok {
+			writes[i].updated = updated
+			continue
+		}
+
+		pendingIndex[path] = len(writes)
+		writes = append(writes, pendingWrite{path: path, original: content, updated: updated})
+	}
+
+	return writes, nil
+}
+
+// commitWrites applies every pending write via a temp-file-then-rename, so
+// each individual file update is atomic. If any write in the batch fails,
+// writes already committed are rolled back to their original content so no
+// partial update is left on disk.
+func commitWrites(writes []pendingWrite) error {
+	var committed []pendingWrite
+
+	rollback := func() {
+		for _, w := range committed {
+			_ = os.WriteFile(w.path, w.original, 0644)
+		}
+	}
+
+	for _, w := range writes {
+		tmpPath := w.path + ".py-version-tmp"
+		if err := os.WriteFile(tmpPath, w.updated, 0644); err != nil {
+			rollback()
+			return fmt.Errorf("failed to stage update for %s: %v", w.path, err)
+		}
+		if err := os.Rename(tmpPath, w.path); err != nil {
+			os.Remove(tmpPath)
+			rollback()
+			return fmt.Errorf("failed to write %s: %v", w.path, err)
+		}
+		committed = append(committed, w)
+	}
+
+	return nil
+}